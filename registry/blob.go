@@ -1,8 +1,7 @@
 package registry
 
 import (
-	"bytes"
-	"fmt"
+	"context"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -14,28 +13,51 @@ import (
 )
 
 func (registry *Registry) DownloadBlob(repository string, digest digest.Digest) (io.ReadCloser, error) {
-	url := registry.url("/v2/%s/blobs/%s", repository, digest)
-	registry.Logf("registry.blob.download url=%s repository=%s digest=%s", url, repository, digest)
+	return registry.DownloadBlobContext(context.Background(), repository, digest)
+}
+
+func (registry *Registry) DownloadBlobContext(ctx context.Context, repository string, digest digest.Digest) (io.ReadCloser, error) {
+	blobUrl := registry.url("/v2/%s/blobs/%s", repository, digest)
+	registry.Logf("registry.blob.download url=%s repository=%s digest=%s", blobUrl, repository, digest)
 
-	resp, err := registry.Client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", blobUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	resp, err := registry.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
 	return resp.Body, nil
 }
 
 func (registry *Registry) GetBlobContent(repository string, digest digest.Digest) ([]byte, error) {
-	url := registry.url("/v2/%s/blobs/%s", repository, digest)
-	registry.Logf("registry.blob.download url=%s repository=%s digest=%s", url, repository, digest)
+	return registry.GetBlobContentContext(context.Background(), repository, digest)
+}
 
-	resp, err := registry.Client.Get(url)
-	if resp != nil {
-		defer resp.Body.Close()
+func (registry *Registry) GetBlobContentContext(ctx context.Context, repository string, digest digest.Digest) ([]byte, error) {
+	blobUrl := registry.url("/v2/%s/blobs/%s", repository, digest)
+	registry.Logf("registry.blob.download url=%s repository=%s digest=%s", blobUrl, repository, digest)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", blobUrl, nil)
+	if err != nil {
+		return nil, err
 	}
+
+	resp, err := registry.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -53,7 +75,11 @@ func (registry *Registry) GetBlobContent(repository string, digest digest.Digest
 // *bytes.Reader or *strings.Reader, then GetBody is populated automatically (as explained in the
 // documentation of http.NewRequest()), so nil can be passed as the getBody parameter.
 func (registry *Registry) UploadBlob(repository string, digest digest.Digest, content io.Reader, getBody func() (io.ReadCloser, error)) error {
-	uploadUrl, err := registry.initiateUpload(repository)
+	return registry.UploadBlobContext(context.Background(), repository, digest, content, getBody)
+}
+
+func (registry *Registry) UploadBlobContext(ctx context.Context, repository string, digest digest.Digest, content io.Reader, getBody func() (io.ReadCloser, error)) error {
+	uploadUrl, err := registry.initiateUploadContext(ctx, repository)
 	if err != nil {
 		return err
 	}
@@ -63,7 +89,7 @@ func (registry *Registry) UploadBlob(repository string, digest digest.Digest, co
 
 	registry.Logf("registry.blob.upload url=%s repository=%s digest=%s", uploadUrl, repository, digest)
 
-	upload, err := http.NewRequest("PUT", uploadUrl.String(), content)
+	upload, err := http.NewRequestWithContext(ctx, "PUT", uploadUrl.String(), content)
 	if err != nil {
 		return err
 	}
@@ -76,96 +102,108 @@ func (registry *Registry) UploadBlob(repository string, digest digest.Digest, co
 	if err != nil {
 		return err
 	}
-	_ = resp.Body.Close()
-	return nil
+	defer resp.Body.Close()
+	return checkResponse(resp)
 }
-func (registry *Registry) UploadBlobChunked(repository string, digest digest.Digest, contBytes []byte) error {
-	chunkSize := 8096000
-	contLength := len(contBytes)
 
-	if contLength <= chunkSize {
-		return registry.UploadBlob(repository, digest, bytes.NewBuffer(contBytes), nil)
-	}
+// UploadBlobChunked uploads contBytes in chunks sized to the registry's
+// advertised minimum (see ResumableUpload), committing with digest once all
+// bytes have been written. For resumable uploads that must survive a process
+// restart, use NewResumableUpload directly instead.
+func (registry *Registry) UploadBlobChunked(repository string, digest digest.Digest, contBytes []byte) error {
+	return registry.UploadBlobChunkedContext(context.Background(), repository, digest, contBytes)
+}
 
-	uploadUrl, err := registry.initiateUpload(repository)
+func (registry *Registry) UploadBlobChunkedContext(ctx context.Context, repository string, digest digest.Digest, contBytes []byte) error {
+	upload, err := registry.NewResumableUploadContext(ctx, repository)
 	if err != nil {
 		return err
 	}
 
-	chunk := contLength / chunkSize
-	lastChSize := contLength % chunkSize
+	if _, err := upload.Write(contBytes); err != nil {
+		return err
+	}
 
-	for ch := 0; ch < chunk; ch++ {
-		rangeStart := ch * chunkSize
-		rangeEnd := rangeStart + chunkSize
-		content := bytes.NewBuffer(contBytes[rangeStart:rangeEnd])
-		upload, err := http.NewRequest("PATCH", uploadUrl.String(), content)
-		if err != nil {
-			return err
-		}
-		upload.Header.Set("Content-Type", "application/octet-stream")
-		upload.Header.Set("Content-Length", strconv.Itoa(chunkSize))
-		contRange := fmt.Sprintf("%d-%d", rangeStart, rangeEnd-1)
-		upload.Header.Set("Content-Range", contRange)
-		registry.Logf("registry.blob.upload url=%s Content-Range=%s", uploadUrl, contRange)
+	return upload.Commit(digest)
+}
 
-		resp, err := registry.Client.Do(upload)
-		if err != nil {
-			return err
-		}
-		_ = resp.Body.Close()
-	}
+// MountOutcome reports how MountBlob was satisfied: either the registry
+// mounted the existing blob directly, or it fell back to a plain upload
+// session that the caller must now push bytes to.
+type MountOutcome int
 
-	q := uploadUrl.Query()
-	q.Set("digest", digest.String())
-	uploadUrl.RawQuery = q.Encode()
+const (
+	MountOutcomeMounted MountOutcome = iota
+	MountOutcomeUploadRequired
+)
 
-	rangeStart := chunk * chunkSize
-	rangeEnd := contLength
-	content := bytes.NewBuffer(contBytes[rangeStart:rangeEnd])
-	upload, err := http.NewRequest("PUT", uploadUrl.String(), content)
-	if err != nil {
-		return err
-	}
-	upload.Header.Set("Content-Type", "application/octet-stream")
-	upload.Header.Set("Content-Length", strconv.Itoa(lastChSize))
-	contRange := fmt.Sprintf("%d-%d", rangeStart, rangeEnd-1)
-	upload.Header.Set("Content-Range", contRange)
+func (registry *Registry) MountBlob(repository string, digest digest.Digest, fromrepo string) (MountOutcome, error) {
+	return registry.MountBlobContext(context.Background(), repository, digest, fromrepo)
+}
 
-	registry.Logf("registry.blob.upload url=%s Content-Range=%s repository=%s digest=%s", uploadUrl, contRange, repository, digest)
-	resp, err := registry.Client.Do(upload)
-	if err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	return nil
+func (registry *Registry) MountBlobContext(ctx context.Context, repository string, digest digest.Digest, fromrepo string) (MountOutcome, error) {
+	outcome, _, err := registry.mountBlob(ctx, repository, digest, fromrepo)
+	return outcome, err
 }
 
-func (registry *Registry) MountBlob(repository string, digest digest.Digest, fromrepo string) error {
-	url := registry.url("/v2/%s/blobs/uploads/?mount=%s&from=%s", repository, digest, fromrepo)
-	registry.Logf("registry.blob.mount url=%s repository=%s digest=%s from=%s", url, repository, digest, fromrepo)
+// mountBlob issues the cross-repo mount request and, on a 202 fallback, also
+// returns the upload session it was handed so callers (MountBlobFrom) can
+// push the blob's bytes without re-initiating an upload.
+func (registry *Registry) mountBlob(ctx context.Context, repository string, dig digest.Digest, fromrepo string) (MountOutcome, *ResumableUpload, error) {
+	mountUrl := registry.url("/v2/%s/blobs/uploads/?mount=%s&from=%s", repository, dig, fromrepo)
+	registry.Logf("registry.blob.mount url=%s repository=%s digest=%s from=%s", mountUrl, repository, dig, fromrepo)
 
-	resp, err := registry.Client.Post(url, "application/octet-stream", nil)
-	if resp != nil {
-		defer resp.Body.Close()
+	req, err := http.NewRequestWithContext(ctx, "POST", mountUrl, nil)
+	if err != nil {
+		return 0, nil, err
 	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := registry.Client.Do(req)
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return MountOutcomeMounted, nil, nil
+	case http.StatusAccepted:
+		upload, err := registry.resumableUploadFromResponse(ctx, resp)
+		if err != nil {
+			return 0, nil, err
+		}
+		return MountOutcomeUploadRequired, upload, nil
+	default:
+		return 0, nil, checkResponse(resp)
+	}
 }
 
 func (registry *Registry) HasBlob(repository string, digest digest.Digest) (bool, error) {
+	return registry.HasBlobContext(context.Background(), repository, digest)
+}
+
+func (registry *Registry) HasBlobContext(ctx context.Context, repository string, digest digest.Digest) (bool, error) {
 	checkUrl := registry.url("/v2/%s/blobs/%s", repository, digest)
 	registry.Logf("registry.blob.check url=%s repository=%s digest=%s", checkUrl, repository, digest)
 
-	resp, err := registry.Client.Head(checkUrl)
+	req, err := http.NewRequestWithContext(ctx, "HEAD", checkUrl, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := registry.Client.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err == nil {
-		return resp.StatusCode == http.StatusOK, nil
+		if resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			return false, checkResponse(resp)
+		}
+		return true, nil
 	}
 
 	urlErr, ok := err.(*url.Error)
@@ -184,16 +222,28 @@ func (registry *Registry) HasBlob(repository string, digest digest.Digest) (bool
 }
 
 func (registry *Registry) BlobMetadata(repository string, digest digest.Digest) (distribution.Descriptor, error) {
+	return registry.BlobMetadataContext(context.Background(), repository, digest)
+}
+
+func (registry *Registry) BlobMetadataContext(ctx context.Context, repository string, digest digest.Digest) (distribution.Descriptor, error) {
 	checkUrl := registry.url("/v2/%s/blobs/%s", repository, digest)
 	registry.Logf("registry.blob.check url=%s repository=%s digest=%s", checkUrl, repository, digest)
 
-	resp, err := registry.Client.Head(checkUrl)
+	req, err := http.NewRequestWithContext(ctx, "HEAD", checkUrl, nil)
+	if err != nil {
+		return distribution.Descriptor{}, err
+	}
+
+	resp, err := registry.Client.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err != nil {
 		return distribution.Descriptor{}, err
 	}
+	if err := checkResponse(resp); err != nil {
+		return distribution.Descriptor{}, err
+	}
 
 	return distribution.Descriptor{
 		Digest: digest,
@@ -202,21 +252,49 @@ func (registry *Registry) BlobMetadata(repository string, digest digest.Digest)
 }
 
 func (registry *Registry) initiateUpload(repository string) (*url.URL, error) {
+	locationUrl, _, err := registry.initiateUploadSessionContext(context.Background(), repository)
+	return locationUrl, err
+}
+
+func (registry *Registry) initiateUploadContext(ctx context.Context, repository string) (*url.URL, error) {
+	locationUrl, _, err := registry.initiateUploadSessionContext(ctx, repository)
+	return locationUrl, err
+}
+
+// initiateUploadSession is like initiateUpload but also surfaces the
+// server-advertised minimum chunk size (OCI-Chunk-Min-Length), needed by
+// ResumableUpload to size its PATCH requests.
+func (registry *Registry) initiateUploadSession(repository string) (*url.URL, int64, error) {
+	return registry.initiateUploadSessionContext(context.Background(), repository)
+}
+
+func (registry *Registry) initiateUploadSessionContext(ctx context.Context, repository string) (*url.URL, int64, error) {
 	initiateUrl := registry.url("/v2/%s/blobs/uploads/", repository)
 	registry.Logf("registry.blob.initiate-upload url=%s repository=%s", initiateUrl, repository)
 
-	resp, err := registry.Client.Post(initiateUrl, "application/octet-stream", nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", initiateUrl, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := registry.Client.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, 0, err
 	}
 
 	location := resp.Header.Get("Location")
 	locationUrl, err := url.Parse(location)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return locationUrl, nil
+
+	minChunkSize, _ := strconv.ParseInt(resp.Header.Get("OCI-Chunk-Min-Length"), 10, 64)
+	return locationUrl, minChunkSize, nil
 }
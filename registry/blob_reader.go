@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+const blobReaderMaxReconnects = 3
+
+// blobReader is an io.ReadSeekCloser over a registry blob. It opens HTTP Range
+// requests lazily as Read/Seek are called rather than buffering the whole
+// blob, and reconnects transparently (re-issuing a Range from the last
+// successful offset) if the underlying stream breaks.
+type blobReader struct {
+	registry      *Registry
+	url           string
+	size          int64
+	acceptsRanges bool
+
+	offset int64
+	body   io.ReadCloser
+}
+
+// NewBlobReader returns a seekable reader over the given blob. It issues a
+// HEAD request to learn the blob's Content-Length and whether the registry
+// advertises "Accept-Ranges: bytes". Read and Seek then drive Range requests
+// against the blob URL on demand, so callers can scan or extract a layer
+// without first buffering it to disk. If the registry doesn't support
+// ranges, the reader falls back to a single sequential stream and Seek
+// only supports the current offset.
+func (registry *Registry) NewBlobReader(repository string, dig digest.Digest) (io.ReadSeekCloser, error) {
+	blobUrl := registry.url("/v2/%s/blobs/%s", repository, dig)
+	registry.Logf("registry.blob.reader url=%s repository=%s digest=%s", blobUrl, repository, dig)
+
+	resp, err := registry.Client.Head(blobUrl)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry: HEAD %s: unexpected status %s", blobUrl, resp.Status)
+	}
+
+	return &blobReader{
+		registry:      registry,
+		url:           blobUrl,
+		size:          resp.ContentLength,
+		acceptsRanges: resp.Header.Get("Accept-Ranges") == "bytes",
+	}, nil
+}
+
+func (r *blobReader) connect() error {
+	req, err := http.NewRequest("GET", r.url, nil)
+	if err != nil {
+		return err
+	}
+	if r.acceptsRanges {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+	}
+
+	resp, err := r.registry.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if r.acceptsRanges && r.offset != 0 {
+			// Server ignored the Range header and sent the blob from byte 0;
+			// stop issuing Range headers, and skip forward to r.offset so the
+			// stream we hand back still starts where the caller expects.
+			r.acceptsRanges = false
+			if _, err := io.CopyN(ioutil.Discard, resp.Body, r.offset); err != nil {
+				resp.Body.Close()
+				return fmt.Errorf("registry: GET %s: server ignored Range and skipping to offset %d failed: %w", r.url, r.offset, err)
+			}
+		}
+	case http.StatusPartialContent:
+		// expected for a mid-stream range request.
+	default:
+		resp.Body.Close()
+		return fmt.Errorf("registry: GET %s: unexpected status %s", r.url, resp.Status)
+	}
+
+	r.body = resp.Body
+	return nil
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		if err := r.connect(); err != nil {
+			return 0, err
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= blobReaderMaxReconnects; attempt++ {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+
+		r.registry.Logf("registry.blob.reader reconnecting url=%s offset=%d err=%s", r.url, r.offset, err)
+		r.body.Close()
+		r.body = nil
+		if !r.acceptsRanges {
+			return 0, err
+		}
+		lastErr = err
+		if connErr := r.connect(); connErr != nil {
+			return 0, connErr
+		}
+	}
+	return 0, lastErr
+}
+
+func (r *blobReader) Seek(offset int64, whence int) (int64, error) {
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = offset
+	case io.SeekCurrent:
+		newOffset = r.offset + offset
+	case io.SeekEnd:
+		newOffset = r.size + offset
+	default:
+		return 0, fmt.Errorf("registry: invalid whence %d", whence)
+	}
+	if newOffset < 0 {
+		return 0, fmt.Errorf("registry: negative seek offset %d", newOffset)
+	}
+	if newOffset == r.offset {
+		return r.offset, nil
+	}
+	if !r.acceptsRanges {
+		return 0, fmt.Errorf("registry: blob %s does not support range requests, cannot seek", r.url)
+	}
+
+	if r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = newOffset
+	return r.offset, nil
+}
+
+func (r *blobReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,52 @@
+package registry
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestBlobReaderConnectSkipsIgnoredRange covers connect()'s fallback when a
+// server advertises Accept-Ranges but then ignores the Range header and
+// returns the full blob from byte 0 (200 instead of 206). The reader must
+// discard up to the requested offset itself, not hand back bytes from the
+// start of the blob as if they belonged at offset.
+func TestBlobReaderConnectSkipsIgnoredRange(t *testing.T) {
+	content := []byte("0123456789abcdef")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Ignore any Range header entirely and always serve the full body,
+		// simulating a non-conforming intermediary.
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	r := &blobReader{
+		registry:      &Registry{Client: server.Client()},
+		url:           server.URL,
+		size:          int64(len(content)),
+		acceptsRanges: true,
+		offset:        10,
+	}
+
+	if err := r.connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer r.body.Close()
+
+	got, err := io.ReadAll(r.body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	want := content[10:]
+	if !bytes.Equal(got, want) {
+		t.Fatalf("connect() returned bytes for offset 10 = %q, want %q", got, want)
+	}
+	if r.acceptsRanges {
+		t.Fatal("acceptsRanges should be disabled after the server ignored Range")
+	}
+}
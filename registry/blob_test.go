@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// TestUploadBlobContextSurfacesRegistryError covers the regression
+// checkResponse exists to prevent: a non-2xx response from the final PUT
+// must come back as a *RegistryError, not be silently treated as success.
+func TestUploadBlobContextSurfacesRegistryError(t *testing.T) {
+	dig := digest.FromString("hello")
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/repo/blobs/uploads/", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			t.Fatalf("unexpected method %s on initiate-upload", req.Method)
+		}
+		w.Header().Set("Location", server.URL+"/v2/repo/blobs/uploads/session-1")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/v2/repo/blobs/uploads/session-1", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPut {
+			t.Fatalf("unexpected method %s on commit", req.Method)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":[{"code":"DIGEST_INVALID","message":"provided digest did not match uploaded content"}]}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	registry := &Registry{Client: server.Client(), URL: server.URL, Logf: func(string, ...interface{}) {}}
+
+	err := registry.UploadBlobContext(context.Background(), "repo", dig, strings.NewReader("hello"), nil)
+	if err == nil {
+		t.Fatal("UploadBlobContext returned nil error for a 400 response")
+	}
+	regErr, ok := err.(*RegistryError)
+	if !ok {
+		t.Fatalf("error type = %T, want *RegistryError", err)
+	}
+	if regErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("StatusCode = %d, want %d", regErr.StatusCode, http.StatusBadRequest)
+	}
+	if len(regErr.Errors) != 1 || regErr.Errors[0].Code != "DIGEST_INVALID" {
+		t.Errorf("Errors = %v, want a single DIGEST_INVALID entry", regErr.Errors)
+	}
+}
+
+// TestHasBlobContextSurfacesRegistryError covers HasBlobContext's non-404,
+// non-200 path: a 500 from the registry must be reported as a
+// *RegistryError, distinct from "blob not found" (404, false/nil).
+func TestHasBlobContextSurfacesRegistryError(t *testing.T) {
+	dig := digest.FromString("hello")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodHead {
+			t.Fatalf("unexpected method %s", req.Method)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	registry := &Registry{Client: server.Client(), URL: server.URL, Logf: func(string, ...interface{}) {}}
+
+	has, err := registry.HasBlobContext(context.Background(), "repo", dig)
+	if has {
+		t.Error("HasBlobContext returned true for a 500 response")
+	}
+	if err == nil {
+		t.Fatal("HasBlobContext returned nil error for a 500 response")
+	}
+	regErr, ok := err.(*RegistryError)
+	if !ok {
+		t.Fatalf("error type = %T, want *RegistryError", err)
+	}
+	if regErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("StatusCode = %d, want %d", regErr.StatusCode, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// RegistryErrorDetail is a single entry of the v2 error schema's "errors"
+// array (https://docs.docker.com/registry/spec/api/#errors).
+type RegistryErrorDetail struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+// RegistryError is returned by checkResponse when a registry responds with a
+// non-2xx status. It carries the HTTP status alongside any structured errors
+// the registry included in the response body, so callers can distinguish,
+// say, a 413 from a 401 instead of getting back a bare "unexpected status".
+type RegistryError struct {
+	StatusCode int
+	Errors     []RegistryErrorDetail
+}
+
+func (e *RegistryError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("registry: request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("registry: request failed with status %d: %s: %s", e.StatusCode, e.Errors[0].Code, e.Errors[0].Message)
+}
+
+// checkResponse returns nil for a 2xx response and leaves resp.Body untouched,
+// so callers can still read it afterwards. For any other status it reads and
+// closes resp.Body and returns a *RegistryError.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	regErr := &RegistryError{StatusCode: resp.StatusCode}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err == nil && len(body) > 0 {
+		var parsed struct {
+			Errors []RegistryErrorDetail `json:"errors"`
+		}
+		if json.Unmarshal(body, &parsed) == nil {
+			regErr.Errors = parsed.Errors
+		}
+	}
+	return regErr
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantNil    bool
+		wantCode   string
+		wantMsg    string
+	}{
+		{
+			name:       "2xx passes through",
+			statusCode: http.StatusCreated,
+			body:       "",
+			wantNil:    true,
+		},
+		{
+			name:       "4xx with v2 error schema body",
+			statusCode: http.StatusBadRequest,
+			body:       `{"errors":[{"code":"DIGEST_INVALID","message":"provided digest did not match uploaded content"}]}`,
+			wantCode:   "DIGEST_INVALID",
+			wantMsg:    "provided digest did not match uploaded content",
+		},
+		{
+			name:       "5xx with empty body",
+			statusCode: http.StatusInsufficientStorage,
+			body:       "",
+		},
+		{
+			name:       "4xx with non-JSON body",
+			statusCode: http.StatusRequestEntityTooLarge,
+			body:       "request entity too large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.statusCode,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(tt.body)),
+			}
+
+			err := checkResponse(resp)
+			if tt.wantNil {
+				if err != nil {
+					t.Fatalf("checkResponse() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("checkResponse() = nil, want a *RegistryError")
+			}
+
+			regErr, ok := err.(*RegistryError)
+			if !ok {
+				t.Fatalf("checkResponse() error type = %T, want *RegistryError", err)
+			}
+			if regErr.StatusCode != tt.statusCode {
+				t.Errorf("StatusCode = %d, want %d", regErr.StatusCode, tt.statusCode)
+			}
+			if tt.wantCode != "" {
+				if len(regErr.Errors) != 1 {
+					t.Fatalf("Errors = %v, want exactly one entry", regErr.Errors)
+				}
+				if regErr.Errors[0].Code != tt.wantCode {
+					t.Errorf("Errors[0].Code = %q, want %q", regErr.Errors[0].Code, tt.wantCode)
+				}
+				if regErr.Errors[0].Message != tt.wantMsg {
+					t.Errorf("Errors[0].Message = %q, want %q", regErr.Errors[0].Message, tt.wantMsg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package registry
+
+import "net/http"
+
+// RoundTripper is the subset of http.RoundTripper an Interceptor forwards to.
+// It lets an Interceptor decide whether to call next and continue the chain,
+// or short-circuit it by returning its own response or error.
+type RoundTripper interface {
+	RoundTrip(req *http.Request) (*http.Response, error)
+}
+
+// Interceptor wraps an outgoing request and its response, similar to an
+// HTTP middleware. Registry.Use composes a chain of interceptors around the
+// registry's inner http.Client, so things like retries, rate limiting, and
+// digest verification can be layered on at client-construction time instead
+// of duplicated at every call site.
+type Interceptor interface {
+	Intercept(req *http.Request, next RoundTripper) (*http.Response, error)
+}
+
+// InterceptorFunc adapts a plain function to Interceptor.
+type InterceptorFunc func(req *http.Request, next RoundTripper) (*http.Response, error)
+
+func (f InterceptorFunc) Intercept(req *http.Request, next RoundTripper) (*http.Response, error) {
+	return f(req, next)
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// interceptorTransport is an http.RoundTripper that runs a request through a
+// chain of Interceptors before (and after) handing it to base.
+type interceptorTransport struct {
+	interceptors []Interceptor
+	base         http.RoundTripper
+}
+
+func (t *interceptorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.chain(0).RoundTrip(req)
+}
+
+func (t *interceptorTransport) chain(i int) RoundTripper {
+	if i >= len(t.interceptors) {
+		return roundTripperFunc(t.base.RoundTrip)
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return t.interceptors[i].Intercept(req, t.chain(i+1))
+	})
+}
+
+// Use installs interceptors around the registry's HTTP client, in the order
+// given: the first interceptor sees each request first and its response
+// last. Calling Use more than once appends to the existing chain.
+func (registry *Registry) Use(interceptors ...Interceptor) {
+	base := registry.Client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if t, ok := base.(*interceptorTransport); ok {
+		t.interceptors = append(t.interceptors, interceptors...)
+		return
+	}
+
+	registry.Client.Transport = &interceptorTransport{
+		interceptors: interceptors,
+		base:         base,
+	}
+}
@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// NewDigestVerifyInterceptor returns an Interceptor that verifies blob GET
+// responses (DownloadBlob, GetBlobContent) against the digest embedded in
+// their request path (".../blobs/<digest>"), streaming the response body
+// through the digest's hash as it's read. A mismatch surfaces as a Read
+// error on the response body instead of the corrupted blob being silently
+// returned. Requests that aren't blob fetches, or whose digest the library
+// doesn't recognize, pass through unverified — in particular HEAD requests
+// (HasBlobContext, BlobMetadataContext, NewBlobReader's initial probe) hit
+// the same ".../blobs/<digest>" path but carry no body to verify, so they're
+// excluded by method rather than just by path.
+func NewDigestVerifyInterceptor() Interceptor {
+	return InterceptorFunc(func(req *http.Request, next RoundTripper) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusOK || req.Method != http.MethodGet {
+			return resp, err
+		}
+
+		want, ok := blobDigestFromPath(req.URL.Path)
+		if !ok {
+			return resp, nil
+		}
+
+		resp.Body = &digestVerifyingBody{body: resp.Body, verifier: want.Verifier(), want: want}
+		return resp, nil
+	})
+}
+
+func blobDigestFromPath(path string) (digest.Digest, bool) {
+	const sep = "/blobs/"
+	idx := strings.Index(path, sep)
+	if idx < 0 {
+		return "", false
+	}
+
+	raw := path[idx+len(sep):]
+	if raw == "" || strings.HasPrefix(raw, "uploads") {
+		return "", false
+	}
+
+	dig, err := digest.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	return dig, true
+}
+
+// digestVerifyingBody wraps a blob response body, feeding every byte read
+// into a digest.Verifier and failing the Read that hits EOF if the digest
+// doesn't match. Once a mismatch is detected it's remembered in mismatchErr
+// and returned on every subsequent Read too — not just the one call that
+// happened to observe EOF — so another interceptor (e.g. logging) earlier in
+// the chain can't read past EOF once, discard that one error, and leave a
+// later caller to see a clean io.EOF as if the blob were fine.
+type digestVerifyingBody struct {
+	body        io.ReadCloser
+	verifier    digest.Verifier
+	want        digest.Digest
+	checked     bool
+	mismatchErr error
+}
+
+func (b *digestVerifyingBody) Read(p []byte) (int, error) {
+	if b.mismatchErr != nil {
+		return 0, b.mismatchErr
+	}
+
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.verifier.Write(p[:n])
+	}
+	if err == io.EOF && !b.checked {
+		b.checked = true
+		if !b.verifier.Verified() {
+			b.mismatchErr = fmt.Errorf("registry: blob content does not match digest %s", b.want)
+			return n, b.mismatchErr
+		}
+	}
+	return n, err
+}
+
+func (b *digestVerifyingBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// TestDigestVerifyAndLoggingInterceptorComposition covers installing the
+// logging interceptor ahead of the digest-verify one (the natural call order
+// registry.Use(NewLoggingInterceptor(...), NewDigestVerifyInterceptor())),
+// with a corrupted blob short enough to fit entirely within the logging
+// interceptor's body preview. The digest mismatch must still reach the
+// caller instead of being swallowed by the preview read.
+func TestDigestVerifyAndLoggingInterceptorComposition(t *testing.T) {
+	const actualContent = "this is not the content the digest was computed for"
+	wantDigest := digest.FromString("something else entirely")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, actualContent)
+	}))
+	defer server.Close()
+
+	registry := &Registry{
+		Client: server.Client(),
+		Logf:   func(string, ...interface{}) {},
+	}
+	// maxBodyLog comfortably covers the whole (short) body, which is exactly
+	// the case that used to let the mismatch slip through unnoticed.
+	registry.Use(NewLoggingInterceptor(registry, nil, 4096), NewDigestVerifyInterceptor())
+
+	url := server.URL + "/v2/repo/blobs/" + wantDigest.String()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := registry.Client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+
+	_, readErr := ioutil.ReadAll(resp.Body)
+	if readErr == nil {
+		t.Fatal("expected a digest mismatch error reading the response body, got nil")
+	}
+	if !strings.Contains(readErr.Error(), "does not match digest") {
+		t.Fatalf("unexpected error: %v", readErr)
+	}
+}
+
+// TestDigestVerifyInterceptorIgnoresHEAD covers HasBlobContext and
+// BlobMetadataContext, which issue HEAD requests against the exact same
+// ".../blobs/<digest>" path as a blob GET and also get back a 200. A HEAD
+// response has no body to verify, so the interceptor must leave it alone
+// rather than wrapping it and reporting a bogus mismatch against the empty
+// body.
+func TestDigestVerifyInterceptorIgnoresHEAD(t *testing.T) {
+	wantDigest := digest.FromString("whatever the real blob contains")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := &Registry{
+		Client: server.Client(),
+		Logf:   func(string, ...interface{}) {},
+	}
+	registry.Use(NewDigestVerifyInterceptor())
+
+	url := server.URL + "/v2/repo/blobs/" + wantDigest.String()
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := registry.Client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		t.Fatalf("reading HEAD response body: %v", err)
+	}
+}
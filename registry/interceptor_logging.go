@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// NewLoggingInterceptor returns an Interceptor that logs each request and
+// response via registry.Logf. Header names in redactHeaders (matched
+// case-insensitively, e.g. "Authorization") are logged as "<redacted>". Up to
+// maxBodyLog bytes of each response body are logged; the full body is still
+// returned to the caller. Request bodies are not logged.
+func NewLoggingInterceptor(registry *Registry, redactHeaders []string, maxBodyLog int) Interceptor {
+	redacted := make(map[string]bool, len(redactHeaders))
+	for _, h := range redactHeaders {
+		redacted[http.CanonicalHeaderKey(h)] = true
+	}
+
+	return InterceptorFunc(func(req *http.Request, next RoundTripper) (*http.Response, error) {
+		start := time.Now()
+		registry.Logf("registry.http.request method=%s url=%s headers=%s", req.Method, req.URL, redactedHeaders(req.Header, redacted))
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			registry.Logf("registry.http.response method=%s url=%s duration=%s err=%s", req.Method, req.URL, time.Since(start), err)
+			return resp, err
+		}
+
+		body, bodyErr := previewBody(resp, maxBodyLog)
+		if bodyErr != nil {
+			registry.Logf("registry.http.response method=%s url=%s status=%s duration=%s body=%q err=%s", req.Method, req.URL, resp.Status, time.Since(start), body, bodyErr)
+			return resp, nil
+		}
+		registry.Logf("registry.http.response method=%s url=%s status=%s duration=%s body=%q", req.Method, req.URL, resp.Status, time.Since(start), body)
+		return resp, nil
+	})
+}
+
+func redactedHeaders(h http.Header, redacted map[string]bool) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if redacted[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{"<redacted>"}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// previewBody reads up to maxBodyLog bytes of resp.Body for logging, then
+// restores resp.Body (preview bytes followed by whatever remains) so the
+// caller can still read the full response. io.EOF/io.ErrUnexpectedEOF just
+// mean the body was shorter than maxBodyLog, which is expected and not
+// reported. Any other error — e.g. a digest mismatch from a later
+// interceptor in the chain — is returned rather than silently dropped:
+// unlike a plain short read, it means the bytes we did get may not be the
+// full, valid response, and the caller reading past the preview will hit
+// this same error again from the wrapped body.
+func previewBody(resp *http.Response, maxBodyLog int) ([]byte, error) {
+	if resp.Body == nil || maxBodyLog <= 0 {
+		return nil, nil
+	}
+
+	preview := make([]byte, maxBodyLog)
+	n, err := io.ReadFull(resp.Body, preview)
+	preview = preview[:n]
+
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(preview), resp.Body),
+		Closer: resp.Body,
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return preview, err
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NewRateLimitInterceptor returns an Interceptor that enforces a token-bucket
+// rate limit of rps requests per second, with the given burst capacity, kept
+// separately per request host. It blocks until a token is available or the
+// request's context is done.
+func NewRateLimitInterceptor(rps float64, burst int) Interceptor {
+	return &rateLimitInterceptor{
+		rps:     rps,
+		burst:   burst,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+type rateLimitInterceptor struct {
+	mu      sync.Mutex
+	rps     float64
+	burst   int
+	buckets map[string]*tokenBucket
+}
+
+func (r *rateLimitInterceptor) Intercept(req *http.Request, next RoundTripper) (*http.Response, error) {
+	if err := r.bucketFor(req.URL.Host).take(req); err != nil {
+		return nil, err
+	}
+	return next.RoundTrip(req)
+}
+
+func (r *rateLimitInterceptor) bucketFor(host string) *tokenBucket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.buckets[host]
+	if !ok {
+		b = &tokenBucket{rps: r.rps, burst: r.burst, tokens: float64(r.burst), last: time.Now()}
+		r.buckets[host] = b
+	}
+	return b
+}
+
+// tokenBucket is a classic token-bucket limiter: tokens refill continuously
+// at rps per second up to burst, and each request consumes one.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rps    float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(req *http.Request) error {
+	for {
+		wait, ok := b.tryTake()
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// tryTake refills the bucket and either consumes a token (returning ok=true)
+// or reports how long the caller should wait before trying again.
+func (b *tokenBucket) tryTake() (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rps
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / b.rps * float64(time.Second)), false
+}
@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NewRetryInterceptor returns an Interceptor that retries requests up to
+// maxRetries times on 5xx and 429 responses (and on network errors), using
+// exponential backoff starting at baseDelay. A 429 or 503 response's
+// Retry-After header, if present, overrides the computed backoff for that
+// attempt. Only requests with a GetBody (or a nil body) are retried, since
+// the request body may have already been partially consumed otherwise.
+func NewRetryInterceptor(maxRetries int, baseDelay time.Duration) Interceptor {
+	return InterceptorFunc(func(req *http.Request, next RoundTripper) (*http.Response, error) {
+		var resp *http.Response
+		var err error
+
+		for attempt := 0; ; attempt++ {
+			resp, err = next.RoundTrip(req)
+			if !shouldRetry(resp, err) || attempt >= maxRetries || !retryable(req) {
+				return resp, err
+			}
+
+			delay := retryDelay(resp, baseDelay, attempt)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+
+			if req.GetBody != nil {
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					return nil, bodyErr
+				}
+				req.Body = body
+			}
+		}
+	})
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func retryable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func retryDelay(resp *http.Response, baseDelay time.Duration, attempt int) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return baseDelay * time.Duration(1<<uint(attempt))
+}
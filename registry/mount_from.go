@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// MountBlobFrom tries to mount dig into repository from each of candidates
+// in turn, stopping at the first one the registry mounts directly (201) and
+// returning that repository. A candidate that errors out (404, 401, etc.,
+// e.g. because dig doesn't actually live there) is expected and doesn't
+// abort the attempt: it's recorded and the next candidate is tried. If a
+// candidate instead falls back to a plain upload session (202), the loop
+// stops there rather than opening further redundant sessions on the
+// remaining candidates; content is consumed once and streamed through that
+// session via the existing chunked-upload path, so replicators and
+// `docker cp`-style tools can dedupe across many possible source repos
+// instead of always re-uploading. If every candidate errors out with no
+// mount and no fallback session, MountBlobFrom returns an error wrapping
+// each candidate's error; an error from content() or from the fallback
+// upload is returned as well.
+func (registry *Registry) MountBlobFrom(repository string, dig digest.Digest, candidates []string, content func() (io.ReadCloser, int64, error)) (string, error) {
+	return registry.MountBlobFromContext(context.Background(), repository, dig, candidates, content)
+}
+
+func (registry *Registry) MountBlobFromContext(ctx context.Context, repository string, dig digest.Digest, candidates []string, content func() (io.ReadCloser, int64, error)) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("registry: no candidate repositories given for mounting %s into %s", dig, repository)
+	}
+
+	var fallback *ResumableUpload
+	var candidateErrs []error
+	for _, candidate := range candidates {
+		outcome, upload, err := registry.mountBlob(ctx, repository, dig, candidate)
+		if err != nil {
+			candidateErrs = append(candidateErrs, fmt.Errorf("%s: %w", candidate, err))
+			continue
+		}
+		if outcome == MountOutcomeMounted {
+			return candidate, nil
+		}
+		fallback = upload
+		break
+	}
+
+	if fallback == nil {
+		return "", fmt.Errorf("registry: could not mount %s into %s from any of %d candidate(s): %w", dig, repository, len(candidates), errors.Join(candidateErrs...))
+	}
+
+	rc, size, err := content()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	// Stream rc through fallback.Write in minChunkSize-sized reads rather
+	// than buffering the whole blob in memory: a multi-GB layer is exactly
+	// the case content()'s streaming ReadCloser signature exists for.
+	buf := make([]byte, fallback.minChunkSize)
+	lr := io.LimitReader(rc, size)
+	for {
+		n, err := io.ReadFull(lr, buf)
+		if n > 0 {
+			if _, werr := fallback.Write(buf[:n]); werr != nil {
+				return "", werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fallback.Commit(dig)
+}
@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// TestMountBlobFromSkipsFailingCandidates covers the expected case where
+// earlier candidates don't actually have the blob (404) or aren't
+// accessible (401): MountBlobFrom must try the next candidate rather than
+// aborting, and must not open an upload session on any candidate beyond the
+// first one that falls back to 202.
+func TestMountBlobFromSkipsFailingCandidates(t *testing.T) {
+	dig := digest.FromString("hello")
+	content := []byte("hello")
+	var uploadSessionsOpened int
+	var mounted []string
+	var server *httptest.Server
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/repo/blobs/uploads/", func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Query().Get("from") {
+		case "missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "forbidden":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "has-it":
+			uploadSessionsOpened++
+			w.Header().Set("Location", server.URL+"/v2/repo/blobs/uploads/session-1")
+			w.WriteHeader(http.StatusAccepted)
+		case "also-has-it":
+			// Should never be reached: the loop must stop at the first 202.
+			uploadSessionsOpened++
+			w.Header().Set("Location", server.URL+"/v2/repo/blobs/uploads/session-2")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Fatalf("unexpected from=%q", req.URL.Query().Get("from"))
+		}
+	})
+	mux.HandleFunc("/v2/repo/blobs/uploads/session-1", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPatch:
+			mounted = append(mounted, string(readAllOrFatal(t, req.Body)))
+			w.Header().Set("Location", "/v2/repo/blobs/uploads/session-1")
+			w.Header().Set("Range", "0-4")
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPut:
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Fatalf("unexpected method %s on session-1", req.Method)
+		}
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	registry := &Registry{Client: server.Client(), URL: server.URL, Logf: func(string, ...interface{}) {}}
+
+	repo, err := registry.MountBlobFrom("repo", dig, []string{"missing", "forbidden", "has-it", "also-has-it"}, func() (io.ReadCloser, int64, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), int64(len(content)), nil
+	})
+	if err != nil {
+		t.Fatalf("MountBlobFrom: %v", err)
+	}
+	if repo != "" {
+		t.Fatalf("expected fallback-upload result (empty repo string), got %q", repo)
+	}
+	if uploadSessionsOpened != 1 {
+		t.Fatalf("opened %d upload sessions, want exactly 1 (the first 202)", uploadSessionsOpened)
+	}
+	if len(mounted) != 1 || string(mounted[0]) != "hello" {
+		t.Fatalf("unexpected bytes uploaded: %q", mounted)
+	}
+}
+
+// TestMountBlobFromAllCandidatesFail covers the case where every candidate
+// genuinely fails: MountBlobFrom must report a single error that reflects
+// all of them, not just the first.
+func TestMountBlobFromAllCandidatesFail(t *testing.T) {
+	dig := digest.FromString("hello")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	registry := &Registry{Client: server.Client(), URL: server.URL, Logf: func(string, ...interface{}) {}}
+
+	_, err := registry.MountBlobFrom("repo", dig, []string{"a", "b"}, func() (io.ReadCloser, int64, error) {
+		t.Fatal("content should not be consumed when no candidate yields an upload session")
+		return nil, 0, nil
+	})
+	if err == nil {
+		t.Fatal("expected an error when every candidate fails")
+	}
+	var regErr *RegistryError
+	if !errors.As(err, &regErr) {
+		t.Fatalf("expected the combined error to wrap a *RegistryError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "a: ") || !strings.Contains(err.Error(), "b: ") {
+		t.Fatalf("expected error to mention both failing candidates, got: %v", err)
+	}
+}
+
+func readAllOrFatal(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,310 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// defaultMinChunkSize is used to size PATCH requests when the registry
+// doesn't advertise OCI-Chunk-Min-Length.
+const defaultMinChunkSize = 5 * 1024 * 1024
+
+// resumableUploadMaxNetworkRetries bounds how many times writeChunk will
+// recover the offset and retry a chunk after a network error, so a link
+// that's down rather than merely flaky doesn't retry forever.
+const resumableUploadMaxNetworkRetries = 3
+
+// networkError marks an error returned by the underlying http.Client.Do call
+// itself (as opposed to a non-2xx response or a protocol-level mismatch), so
+// writeChunk knows it's safe to recover the offset and retry.
+type networkError struct{ err error }
+
+func (e *networkError) Error() string { return e.err.Error() }
+func (e *networkError) Unwrap() error { return e.err }
+
+// ResumableUpload drives a single chunked blob upload session, following the
+// registry upload spec's Location/Range handshake: the server may move the
+// upload to a different Location between PATCHes, and the offset it reports
+// back is authoritative. Callers that need to survive a process restart can
+// persist State() and recreate the session with ResumeUpload.
+type ResumableUpload struct {
+	registry     *Registry
+	ctx          context.Context
+	location     *url.URL
+	offset       int64
+	minChunkSize int64
+}
+
+// NewResumableUpload starts a new upload session for repository and returns a
+// ResumableUpload ready to accept chunks via Write.
+func (registry *Registry) NewResumableUpload(repository string) (*ResumableUpload, error) {
+	return registry.NewResumableUploadContext(context.Background(), repository)
+}
+
+func (registry *Registry) NewResumableUploadContext(ctx context.Context, repository string) (*ResumableUpload, error) {
+	location, minChunkSize, err := registry.initiateUploadSessionContext(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+	if minChunkSize <= 0 {
+		minChunkSize = defaultMinChunkSize
+	}
+
+	return &ResumableUpload{
+		registry:     registry,
+		ctx:          ctx,
+		location:     location,
+		minChunkSize: minChunkSize,
+	}, nil
+}
+
+// resumableUploadFromResponse builds a ResumableUpload from a 202 response to
+// an initiate-upload or mount-fallback request, picking up the Location and
+// OCI-Chunk-Min-Length it carries rather than starting a fresh session.
+func (registry *Registry) resumableUploadFromResponse(ctx context.Context, resp *http.Response) (*ResumableUpload, error) {
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return nil, err
+	}
+
+	minChunkSize, _ := strconv.ParseInt(resp.Header.Get("OCI-Chunk-Min-Length"), 10, 64)
+	if minChunkSize <= 0 {
+		minChunkSize = defaultMinChunkSize
+	}
+
+	return &ResumableUpload{
+		registry:     registry,
+		ctx:          ctx,
+		location:     location,
+		minChunkSize: minChunkSize,
+	}, nil
+}
+
+// ResumeUpload recreates a ResumableUpload from state previously returned by
+// State, so an interrupted multi-GB push can continue from where it left off.
+func ResumeUpload(registry *Registry, location url.URL, offset int64) *ResumableUpload {
+	return &ResumableUpload{
+		registry:     registry,
+		ctx:          context.Background(),
+		location:     &location,
+		offset:       offset,
+		minChunkSize: defaultMinChunkSize,
+	}
+}
+
+// State returns the current upload Location and byte offset, suitable for
+// persisting and passing to ResumeUpload after a restart.
+func (u *ResumableUpload) State() (url.URL, int64) {
+	return *u.location, u.offset
+}
+
+// Write uploads p as one or more PATCH chunks of the server-advertised
+// minimum chunk size (the last chunk holds the rest of p and may be
+// shorter), and advances the upload's offset by
+// the amount the server acknowledges. On a network error or a 416 response it
+// queries the upload URL to recover the last-received offset and retries the
+// remainder of p from there, up to resumableUploadMaxNetworkRetries times per
+// chunk.
+func (u *ResumableUpload) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		end := written + int(u.minChunkSize)
+		if end > len(p) {
+			end = len(p)
+		}
+		n, err := u.writeChunk(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// writeChunk drives chunk to completion, retrying the unsent remainder from
+// a recovered offset whenever writeChunkOnce reports a networkError.
+func (u *ResumableUpload) writeChunk(chunk []byte) (int, error) {
+	sent := 0
+	for attempt := 0; ; attempt++ {
+		n, err := u.writeChunkOnce(chunk[sent:])
+		sent += n
+		if err == nil {
+			return sent, nil
+		}
+
+		var netErr *networkError
+		if !errors.As(err, &netErr) || attempt >= resumableUploadMaxNetworkRetries {
+			return sent, err
+		}
+
+		staleOffset := u.offset
+		if recoverErr := u.recoverOffset(); recoverErr != nil {
+			return sent, fmt.Errorf("registry: PATCH %s: %w (and failed to recover offset: %v)", u.location, err, recoverErr)
+		}
+		if acked := u.offset - staleOffset; acked > 0 {
+			sent += int(acked)
+		}
+		u.registry.Logf("registry.blob.upload.retry url=%s offset=%d attempt=%d err=%s", u.location, u.offset, attempt+1, err)
+	}
+}
+
+func (u *ResumableUpload) writeChunkOnce(chunk []byte) (int, error) {
+	rangeStart := u.offset
+	rangeEnd := rangeStart + int64(len(chunk)) - 1
+
+	req, err := http.NewRequestWithContext(u.ctx, "PATCH", u.location.String(), bytes.NewReader(chunk))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Length", strconv.Itoa(len(chunk)))
+	req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", rangeStart, rangeEnd))
+	u.registry.Logf("registry.blob.upload.chunk url=%s Content-Range=%s", u.location, req.Header.Get("Content-Range"))
+
+	resp, err := u.registry.Client.Do(req)
+	if err != nil {
+		return 0, &networkError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		staleOffset := u.offset
+		if err := u.recoverOffset(); err != nil {
+			return 0, err
+		}
+		acked := u.offset - staleOffset
+		if acked <= 0 || acked > int64(len(chunk)) {
+			return 0, fmt.Errorf("registry: PATCH %s: server reported offset %d after 416, expected progress within [%d, %d]", u.location, u.offset, staleOffset, staleOffset+int64(len(chunk)))
+		}
+		return int(acked), nil
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return 0, checkResponse(resp)
+	}
+
+	if err := u.advance(resp, rangeEnd); err != nil {
+		return 0, err
+	}
+	return len(chunk), nil
+}
+
+// advance updates location/offset from a PATCH response, verifying the
+// returned Range matches what was sent.
+func (u *ResumableUpload) advance(resp *http.Response, expectedEnd int64) error {
+	if loc := resp.Header.Get("Location"); loc != "" {
+		next, err := url.Parse(loc)
+		if err != nil {
+			return err
+		}
+		u.location = u.location.ResolveReference(next)
+	}
+
+	rng := resp.Header.Get("Range")
+	if rng == "" {
+		return fmt.Errorf("registry: PATCH %s: response missing Range header", u.location)
+	}
+	_, end, err := parseContentRange(rng)
+	if err != nil {
+		return err
+	}
+	if end != expectedEnd {
+		return fmt.Errorf("registry: PATCH %s: server acknowledged range ending at %d, expected %d", u.location, end, expectedEnd)
+	}
+	u.offset = end + 1
+	return nil
+}
+
+// recoverOffset issues a GET on the upload URL to recover the last-received
+// offset after a network error or a 416 response, per the upload spec.
+func (u *ResumableUpload) recoverOffset() error {
+	req, err := http.NewRequestWithContext(u.ctx, "GET", u.location.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := u.registry.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	rng := resp.Header.Get("Range")
+	if rng == "" {
+		return fmt.Errorf("registry: GET %s: response missing Range header", u.location)
+	}
+	_, end, err := parseContentRange(rng)
+	if err != nil {
+		return err
+	}
+	u.offset = end + 1
+	return nil
+}
+
+// Commit finalizes the upload with the given digest, verifying the uploaded
+// bytes hash to it.
+func (u *ResumableUpload) Commit(dig digest.Digest) error {
+	q := u.location.Query()
+	q.Set("digest", dig.String())
+	u.location.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(u.ctx, "PUT", u.location.String(), nil)
+	if err != nil {
+		return err
+	}
+	u.registry.Logf("registry.blob.upload.commit url=%s digest=%s", u.location, dig)
+
+	resp, err := u.registry.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return checkResponse(resp)
+	}
+	return nil
+}
+
+// Cancel aborts the upload session, releasing any resources the registry is
+// holding for it.
+func (u *ResumableUpload) Cancel() error {
+	req, err := http.NewRequestWithContext(u.ctx, "DELETE", u.location.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := u.registry.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return checkResponse(resp)
+	}
+	return nil
+}
+
+// parseContentRange parses the "<start>-<end>" Range/Content-Range value the
+// registry returns from PATCH and GET-recovery responses.
+func parseContentRange(rng string) (start, end int64, err error) {
+	parts := strings.SplitN(rng, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("registry: malformed range %q", rng)
+	}
+	start, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("registry: malformed range %q: %w", rng, err)
+	}
+	end, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("registry: malformed range %q: %w", rng, err)
+	}
+	return start, end, nil
+}
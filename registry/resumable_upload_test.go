@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// flakyPatchTransport fails the first PATCH request made through it (as if
+// the connection dropped after the server had already buffered some bytes),
+// then forwards everything else to inner.
+type flakyPatchTransport struct {
+	inner         http.RoundTripper
+	failNextPATCH bool
+}
+
+func (t *flakyPatchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPatch && t.failNextPATCH {
+		t.failNextPATCH = false
+		return nil, errors.New("simulated connection reset")
+	}
+	return t.inner.RoundTrip(req)
+}
+
+// TestResumableUploadRetriesNetworkErrorFromRecoveredOffset covers
+// writeChunk's documented behavior: on a network error it must recover the
+// last-received offset via GET and retry only the unsent remainder, rather
+// than giving up after the one failed PATCH.
+func TestResumableUploadRetriesNetworkErrorFromRecoveredOffset(t *testing.T) {
+	content := []byte("0123456789")
+	var patchesSeen []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			// Simulate the server having actually received the first 5 bytes
+			// despite the client observing a network error.
+			w.Header().Set("Range", "0-4")
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPatch:
+			patchesSeen = append(patchesSeen, req.Header.Get("Content-Range"))
+			w.Header().Set("Location", "/upload")
+			w.Header().Set("Range", req.Header.Get("Content-Range"))
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Fatalf("unexpected method %s", req.Method)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport = &flakyPatchTransport{inner: client.Transport, failNextPATCH: true}
+
+	loc, err := url.Parse(server.URL + "/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := ResumeUpload(&Registry{Client: client, Logf: func(string, ...interface{}) {}}, *loc, 0)
+	upload.minChunkSize = int64(len(content))
+
+	n, err := upload.Write(content)
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if n != len(content) {
+		t.Fatalf("Write returned n=%d, want %d", n, len(content))
+	}
+	if _, offset := upload.State(); offset != int64(len(content)) {
+		t.Fatalf("final offset = %d, want %d", offset, len(content))
+	}
+
+	want := []string{"5-9"}
+	if fmt.Sprint(patchesSeen) != fmt.Sprint(want) {
+		t.Fatalf("PATCH Content-Ranges seen = %v, want only the recovered remainder %v (first chunk should not be resent)", patchesSeen, want)
+	}
+}